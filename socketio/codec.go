@@ -0,0 +1,96 @@
+package socketio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Codec encodes a batch of Messages for a single poll response and
+// decodes a batch of Messages out of a request body. Message.Binary
+// controls whether a packet is framed as binary or text.
+type Codec interface {
+	Encode(w io.Writer, msgs []Message) error
+	Decode(data []byte) ([]Message, error)
+}
+
+// v1Codec implements the length-prefixed, binary-aware packet framing
+// used by Socket.IO v1 clients: each packet is a type byte (0 for text,
+// 1 for binary), its length in decimal, a 0xFF separator, and then the
+// payload.
+type v1Codec struct{}
+
+// DefaultCodec is the Codec used by transports that don't negotiate one
+// of their own.
+var DefaultCodec Codec = v1Codec{}
+
+const (
+	packetTypeString byte = 0
+	packetTypeBinary byte = 1
+	packetSeparator  byte = 0xFF
+)
+
+func (v1Codec) Encode(w io.Writer, msgs []Message) error {
+	for _, m := range msgs {
+		typ := packetTypeString
+		if m.Binary() {
+			typ = packetTypeBinary
+		}
+
+		if _, err := w.Write([]byte{typ}); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, strconv.Itoa(len(m.Data()))); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte{packetSeparator}); err != nil {
+			return err
+		}
+		if _, err := w.Write(m.Data()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v1Codec) Decode(data []byte) ([]Message, error) {
+	var msgs []Message
+
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errors.New("socketio: truncated packet header")
+		}
+
+		typ := data[0]
+		rest := data[1:]
+
+		sep := bytes.IndexByte(rest, packetSeparator)
+		if sep < 0 {
+			return nil, errors.New("socketio: missing packet length separator")
+		}
+
+		length, err := strconv.Atoi(string(rest[:sep]))
+		if err != nil {
+			return nil, fmt.Errorf("socketio: invalid packet length: %w", err)
+		}
+		if length < 0 {
+			return nil, fmt.Errorf("socketio: invalid packet length: %d", length)
+		}
+
+		payload := rest[sep+1:]
+		if len(payload) < length {
+			return nil, errors.New("socketio: truncated packet payload")
+		}
+
+		if typ == packetTypeBinary {
+			msgs = append(msgs, NewBinaryMessage(payload[:length]))
+		} else {
+			msgs = append(msgs, NewMessage(payload[:length]))
+		}
+		data = payload[length:]
+	}
+
+	return msgs, nil
+}
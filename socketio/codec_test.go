@@ -0,0 +1,51 @@
+package socketio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestV1CodecRoundTrip(t *testing.T) {
+	msgs := []Message{
+		NewMessage([]byte("hello")),
+		NewBinaryMessage([]byte{0x00, 0x01, 0xff, 0x02}),
+		NewMessage([]byte("")),
+	}
+
+	buf := new(bytes.Buffer)
+	if err := DefaultCodec.Encode(buf, msgs); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	decoded, err := DefaultCodec.Decode(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if len(decoded) != len(msgs) {
+		t.Fatalf("got %d messages, want %d", len(decoded), len(msgs))
+	}
+	for i, want := range msgs {
+		got := decoded[i]
+		if got.Binary() != want.Binary() {
+			t.Errorf("message %d: Binary() = %v, want %v", i, got.Binary(), want.Binary())
+		}
+		if !bytes.Equal(got.Data(), want.Data()) {
+			t.Errorf("message %d: Data() = %q, want %q", i, got.Data(), want.Data())
+		}
+	}
+}
+
+func TestV1CodecDecodeRejectsNegativeLength(t *testing.T) {
+	// "0-1\xff" is a text packet whose length field parses as -1; this
+	// used to reach payload[:length] with length < 0 and panic.
+	if _, err := DefaultCodec.Decode([]byte("0-1\xff")); err == nil {
+		t.Fatal("Decode returned no error for a negative packet length")
+	}
+}
+
+func TestV1CodecDecodeRejectsTruncatedPayload(t *testing.T) {
+	if _, err := DefaultCodec.Decode([]byte("010\xffhi")); err == nil {
+		t.Fatal("Decode returned no error for a payload shorter than its declared length")
+	}
+}
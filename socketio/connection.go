@@ -0,0 +1,231 @@
+package socketio
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Connection represents the server side of a single client's Socket.IO
+// session. It owns the current socket for the session and hands off to a
+// new one on every poll/upgrade, so the transports underneath it are free
+// to use a fresh http.ResponseWriter per request rather than a single
+// long-lived net.Conn.
+//
+// It also buffers messages emitted between polls: Emit appends to
+// pending and, if a poll is parked waiting for data, wakes it; Poll
+// either flushes pending immediately or parks until Emit wakes it or the
+// read timeout passed to Poll elapses.
+type Connection struct {
+	sessionid string
+	socket    socket
+
+	mu      sync.Mutex
+	pending []Message
+	parked  *parkedPoll
+}
+
+// parkedPoll is the state a blocked Poll call registers on the
+// Connection so that Emit can wake it, and a later overlapping Poll call
+// can evict it, without the two calls' goroutines touching each other's
+// socket directly.
+type parkedPoll struct {
+	socket socket
+	wake   chan struct{} // closed by Emit when data arrives
+	evict  chan struct{} // closed by a later Poll taking over this session
+}
+
+// NewConnection creates a Connection bound to the given session id.
+func NewConnection(sessionid string) *Connection {
+	return &Connection{sessionid: sessionid}
+}
+
+// SessionID returns the id used to correlate requests belonging to this
+// connection.
+func (c *Connection) SessionID() string {
+	return c.sessionid
+}
+
+// Accept binds the connection to the socket produced for this leg of the
+// request and runs proceed once the handshake succeeds.
+func (c *Connection) Accept(s socket, w http.ResponseWriter, req *http.Request, proceed func()) error {
+	c.socket = s
+	return s.accept(w, req, proceed)
+}
+
+func (c *Connection) Read(p []byte) (int, error) {
+	if c.socket == nil {
+		return 0, ErrNotConnected
+	}
+	return c.socket.Read(p)
+}
+
+// ReadMessages reads the whole body of the currently accepted socket
+// (an upload request) and decodes it with DefaultCodec, so binary and
+// multi-packet uploads come back as the Messages they represent rather
+// than an undecoded blob. A body that isn't in DefaultCodec's framing
+// (plain legacy clients) comes back as a single text Message instead of
+// failing outright.
+func (c *Connection) ReadMessages() ([]Message, error) {
+	if c.socket == nil {
+		return nil, ErrNotConnected
+	}
+
+	raw, err := io.ReadAll(c.socket)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	if msgs, err := DefaultCodec.Decode(raw); err == nil {
+		return msgs, nil
+	}
+	return []Message{NewMessage(raw)}, nil
+}
+
+func (c *Connection) Write(p []byte) (int, error) {
+	if c.socket == nil {
+		return 0, ErrNotConnected
+	}
+	return c.socket.Write(p)
+}
+
+// BinaryMessage marks a payload that should be sent as a binary frame on
+// transports that support it.
+type BinaryMessage []byte
+
+// WriteBinary sends p as a binary message.
+func (c *Connection) WriteBinary(p BinaryMessage) (int, error) {
+	return c.WriteMessages([]Message{NewBinaryMessage(p)})
+}
+
+// WriteMessages writes a batch of Messages in one shot, letting
+// transports that support it (xhr-polling, websocket) frame binary and
+// text packets appropriately instead of losing that distinction over a
+// plain io.Writer.
+func (c *Connection) WriteMessages(msgs []Message) (int, error) {
+	if c.socket == nil {
+		return 0, ErrNotConnected
+	}
+	return writeMessagesTo(c.socket, msgs)
+}
+
+// writeMessagesTo writes msgs to s directly, without going through a
+// Connection, so callers juggling more than one socket for the same
+// Connection (Poll/flush) can't accidentally write to the wrong one.
+func writeMessagesTo(s socket, msgs []Message) (int, error) {
+	if mw, ok := s.(messageWriter); ok {
+		return mw.WriteMessages(msgs)
+	}
+
+	var n int
+	for _, m := range msgs {
+		written, err := s.Write(m.Data())
+		n += written
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Emit appends msgs to the session's outbound buffer, waking a parked
+// poll if one is waiting for data.
+func (c *Connection) Emit(msgs ...Message) {
+	c.mu.Lock()
+	c.pending = append(c.pending, msgs...)
+	parked := c.parked
+	c.parked = nil
+	c.mu.Unlock()
+
+	if parked != nil {
+		close(parked.wake)
+	}
+}
+
+// Poll serves a single long-poll request for the session. Buffered
+// messages are flushed immediately; otherwise the request parks until
+// Emit wakes it or rtimeout elapses, at which point it returns an empty
+// poll. A poll that arrives while an earlier one is still parked for the
+// same session evicts that older poll, which closes its own socket and
+// returns without ever touching this poll's socket or buffered data.
+func (c *Connection) Poll(s socket, w http.ResponseWriter, req *http.Request, rtimeout time.Duration) error {
+	if err := c.Accept(s, w, req, func() {}); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if stale := c.parked; stale != nil && stale.socket != s {
+		c.parked = nil
+		c.mu.Unlock()
+		close(stale.evict)
+		c.mu.Lock()
+	}
+
+	if len(c.pending) > 0 {
+		msgs := c.pending
+		c.pending = nil
+		c.mu.Unlock()
+		return flushTo(s, msgs)
+	}
+
+	pp := &parkedPoll{socket: s, wake: make(chan struct{}), evict: make(chan struct{})}
+	c.parked = pp
+	c.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if rtimeout != 0 {
+		timer := time.NewTimer(rtimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-pp.wake:
+	case <-timeout:
+	case <-s.Context().Done():
+	case <-pp.evict:
+		// A later poll for this session has taken over; it owns
+		// c.pending now, so just close our own socket cleanly and stop,
+		// rather than racing it for the buffered messages.
+		return s.Close()
+	}
+
+	c.mu.Lock()
+	if c.parked == pp {
+		c.parked = nil
+	}
+	msgs := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	return flushTo(s, msgs)
+}
+
+// flushTo writes msgs to s: nothing for an empty poll, the plain
+// payload for a single message, or all of them multiplexed onto one
+// response using the classic Socket.IO �<len>�<payload> packet
+// framing when more than one message arrived while a poll was parked.
+func flushTo(s socket, msgs []Message) error {
+	switch len(msgs) {
+	case 0:
+		_, err := s.Write(nil)
+		return err
+	case 1:
+		_, err := writeMessagesTo(s, msgs)
+		return err
+	default:
+		_, err := s.Write(encodeMultiPacket(msgs))
+		return err
+	}
+}
+
+func (c *Connection) Close() error {
+	if c.socket == nil {
+		return ErrNotConnected
+	}
+	return c.socket.Close()
+}
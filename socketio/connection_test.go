@@ -0,0 +1,180 @@
+package socketio
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSocket is a minimal in-memory socket used to exercise Connection
+// without a real transport.
+type fakeSocket struct {
+	mu     sync.Mutex
+	closed bool
+	writes [][]byte
+	ctx    context.Context
+}
+
+func newFakeSocket() *fakeSocket {
+	return &fakeSocket{ctx: context.Background()}
+}
+
+func (s *fakeSocket) String() string      { return "fake" }
+func (s *fakeSocket) Transport() Transport { return nil }
+
+func (s *fakeSocket) Context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ctx
+}
+
+func (s *fakeSocket) accept(w http.ResponseWriter, req *http.Request, proceed func()) error {
+	proceed()
+	return nil
+}
+
+func (s *fakeSocket) Read(p []byte) (int, error) { return 0, nil }
+
+func (s *fakeSocket) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes = append(s.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (s *fakeSocket) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *fakeSocket) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *fakeSocket) writeCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.writes)
+}
+
+func TestConnectionPollFlushesBufferedMessages(t *testing.T) {
+	conn := NewConnection("sid-flush")
+	conn.Emit(NewMessage([]byte("hello")))
+
+	s := newFakeSocket()
+	if err := conn.Poll(s, nil, &http.Request{}, time.Second); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+
+	if got := s.writeCount(); got != 1 {
+		t.Fatalf("got %d writes, want 1", got)
+	}
+	if string(s.writes[0]) != "hello" {
+		t.Fatalf("wrote %q, want %q", s.writes[0], "hello")
+	}
+}
+
+func TestConnectionPollParksThenEmitWakesIt(t *testing.T) {
+	conn := NewConnection("sid-park")
+	s := newFakeSocket()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- conn.Poll(s, nil, &http.Request{}, time.Minute)
+	}()
+
+	waitUntilParked(t, conn)
+	conn.Emit(NewMessage([]byte("world")))
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Poll: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Poll did not return after Emit")
+	}
+
+	if got := s.writeCount(); got != 1 || string(s.writes[0]) != "world" {
+		t.Fatalf("writes = %v, want one write of %q", s.writes, "world")
+	}
+}
+
+// TestConnectionPollEvictsStalePollWithoutCrossWiring reproduces two
+// overlapping polls on the same session: the first parks waiting for
+// data, the second arrives before any data has been emitted. The first
+// must be evicted and closed cleanly without ever being written to, and
+// a subsequent Emit must reach the new poll rather than the evicted one.
+func TestConnectionPollEvictsStalePollWithoutCrossWiring(t *testing.T) {
+	conn := NewConnection("sid-evict")
+	stale := newFakeSocket()
+
+	staleDone := make(chan error, 1)
+	go func() {
+		staleDone <- conn.Poll(stale, nil, &http.Request{}, time.Minute)
+	}()
+
+	waitUntilParked(t, conn)
+
+	// A second poll for the same session arrives before anything was
+	// emitted; it must evict the first rather than park alongside it.
+	fresh := newFakeSocket()
+	freshDone := make(chan error, 1)
+	go func() {
+		freshDone <- conn.Poll(fresh, nil, &http.Request{}, time.Minute)
+	}()
+
+	select {
+	case err := <-staleDone:
+		if err != nil {
+			t.Fatalf("Poll (stale): %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stale poll was not evicted promptly")
+	}
+
+	if !stale.isClosed() {
+		t.Error("evicted poll's socket was never closed")
+	}
+	if got := stale.writeCount(); got != 0 {
+		t.Errorf("evicted poll received %d writes, want 0 (messages must not cross-wire to the stale socket)", got)
+	}
+
+	waitUntilParked(t, conn)
+	conn.Emit(NewMessage([]byte("for-the-new-poll")))
+
+	select {
+	case err := <-freshDone:
+		if err != nil {
+			t.Fatalf("Poll (fresh): %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("fresh poll did not return after Emit")
+	}
+
+	if got := fresh.writeCount(); got != 1 || string(fresh.writes[0]) != "for-the-new-poll" {
+		t.Errorf("fresh poll writes = %v, want one write of %q", fresh.writes, "for-the-new-poll")
+	}
+}
+
+// waitUntilParked blocks until conn has a poll parked, or fails the test.
+func waitUntilParked(t *testing.T, conn *Connection) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		conn.mu.Lock()
+		parked := conn.parked != nil
+		conn.mu.Unlock()
+		if parked {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("timed out waiting for a poll to park")
+}
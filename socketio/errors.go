@@ -0,0 +1,17 @@
+package socketio
+
+import "errors"
+
+var (
+	// ErrConnected is returned when accept is called on a socket that is
+	// already connected.
+	ErrConnected = errors.New("socket already connected")
+
+	// ErrNotConnected is returned when an operation requires a connected
+	// socket but the socket hasn't completed its handshake yet.
+	ErrNotConnected = errors.New("socket not connected")
+
+	// ErrUpgradeRequired is returned when the underlying http.ResponseWriter
+	// doesn't support the features (flushing, hijacking) a transport needs.
+	ErrUpgradeRequired = errors.New("socket transport not supported by response writer")
+)
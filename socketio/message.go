@@ -0,0 +1,29 @@
+package socketio
+
+// Message is a single packet payload together with whether it should be
+// delivered as binary (an ArrayBuffer/[]byte on the client) or text.
+type Message struct {
+	data   []byte
+	binary bool
+}
+
+// NewMessage wraps data as a text message.
+func NewMessage(data []byte) Message {
+	return Message{data: data}
+}
+
+// NewBinaryMessage wraps data as a binary message.
+func NewBinaryMessage(data []byte) Message {
+	return Message{data: data, binary: true}
+}
+
+// Data returns the raw payload.
+func (m Message) Data() []byte {
+	return m.data
+}
+
+// Binary reports whether the payload should be delivered as a binary
+// frame rather than text.
+func (m Message) Binary() bool {
+	return m.binary
+}
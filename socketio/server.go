@@ -0,0 +1,88 @@
+package socketio
+
+import (
+	"net/http"
+	"time"
+)
+
+// Server routes incoming HTTP requests to the Connection for the
+// client's Socket.IO session, looked up by the `sid` query parameter,
+// so that Connection.Poll/Emit are what actually answer polls instead
+// of each transport responding to every request in isolation.
+type Server struct {
+	transports map[string]Transport
+	sessions   *sessionStore
+	rtimeout   time.Duration
+
+	// OnMessage, when set, is called with the Messages decoded out of
+	// every upload (a POST carrying client-to-server packets).
+	OnMessage func(sid string, msgs []Message)
+}
+
+// NewServer creates a Server dispatching across transports, keyed by
+// their Resource() name as sent in the `?transport=` query parameter.
+func NewServer(rtimeout time.Duration, transports ...Transport) *Server {
+	byResource := make(map[string]Transport, len(transports))
+	for _, t := range transports {
+		byResource[t.Resource()] = t
+	}
+	return &Server{
+		transports: byResource,
+		sessions:   newSessionStore(),
+		rtimeout:   rtimeout,
+	}
+}
+
+// Session returns the Connection for sid, creating it on the session's
+// first request, so callers can Emit to it from outside the
+// request/response cycle that's currently polling it.
+func (srv *Server) Session(sid string) *Connection {
+	return srv.sessions.GetOrCreate(sid)
+}
+
+// ServeHTTP implements http.Handler. GETs are long-polls served through
+// Connection.Poll; POSTs are uploads decoded through Connection.ReadMessages
+// and handed to OnMessage.
+func (srv *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	t, ok := srv.transports[req.URL.Query().Get("transport")]
+	if !ok {
+		http.Error(w, "socketio: unknown transport", http.StatusBadRequest)
+		return
+	}
+
+	sid := req.URL.Query().Get("sid")
+	if sid == "" {
+		http.Error(w, "socketio: missing sid", http.StatusBadRequest)
+		return
+	}
+
+	conn := srv.Session(sid)
+	s := t.newSocket()
+
+	if req.Method == http.MethodPost {
+		srv.serveUpload(conn, s, w, req)
+		return
+	}
+
+	conn.Poll(s, w, req, srv.rtimeout)
+}
+
+func (srv *Server) serveUpload(conn *Connection, s socket, w http.ResponseWriter, req *http.Request) {
+	if err := conn.Accept(s, w, req, func() {}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer s.Close()
+
+	msgs, err := conn.ReadMessages()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if srv.OnMessage != nil && len(msgs) > 0 {
+		srv.OnMessage(conn.SessionID(), msgs)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,56 @@
+package socketio
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// sessionStore tracks the Connection for every session id currently in
+// play, so that concurrent requests for the same session (a poller and
+// a writer, or two overlapping polls) reach the same buffer instead of
+// racing on independent state.
+type sessionStore struct {
+	mu   sync.Mutex
+	byID map[string]*Connection
+}
+
+// newSessionStore creates an empty sessionStore.
+func newSessionStore() *sessionStore {
+	return &sessionStore{byID: make(map[string]*Connection)}
+}
+
+// GetOrCreate returns the Connection for sessionid, creating one on the
+// session's first request.
+func (s *sessionStore) GetOrCreate(sessionid string) *Connection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.byID[sessionid]; ok {
+		return c
+	}
+
+	c := NewConnection(sessionid)
+	s.byID[sessionid] = c
+	return c
+}
+
+// Remove forgets sessionid, e.g. once the client has disconnected for good.
+func (s *sessionStore) Remove(sessionid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.byID, sessionid)
+}
+
+// encodeMultiPacket frames multiple packets coalesced into a single poll
+// response using the classic Socket.IO delimiter: each packet is
+// preceded by �<len>� so the client can split the response
+// back into its individual messages.
+func encodeMultiPacket(msgs []Message) []byte {
+	buf := new(bytes.Buffer)
+	for _, m := range msgs {
+		fmt.Fprintf(buf, "�%d�", len(m.Data()))
+		buf.Write(m.Data())
+	}
+	return buf.Bytes()
+}
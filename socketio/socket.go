@@ -0,0 +1,38 @@
+package socketio
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// socket is the low-level interface a transport implements to exchange
+// bytes with a single client over a single HTTP request/response (polling
+// transports) or for the lifetime of the connection (websocket).
+type socket interface {
+	io.ReadWriteCloser
+
+	// String returns the verbose representation of the socket.
+	String() string
+
+	// Transport returns the transport the socket is based on.
+	Transport() Transport
+
+	// accept takes ownership of the http.ResponseWriter/Request pair for
+	// this leg of the connection and calls proceed if it succeeds.
+	accept(w http.ResponseWriter, req *http.Request, proceed func()) error
+
+	// Context returns the context of the request currently bound to the
+	// socket, so callers can watch for client disconnection instead of
+	// relying on deadlines set on the raw connection.
+	Context() context.Context
+}
+
+// messageWriter is implemented by sockets that can write a batch of
+// Messages directly, letting the transport pick the most efficient
+// framing (binary vs. base64 on xhr-polling, native binary frames on
+// websocket) instead of losing that distinction through a plain
+// io.Writer. The jsonp-polling socket does not implement it.
+type messageWriter interface {
+	WriteMessages(msgs []Message) (int, error)
+}
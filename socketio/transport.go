@@ -0,0 +1,34 @@
+package socketio
+
+import "time"
+
+// Transport is implemented by the various ways a client can exchange
+// Socket.IO packets with the server (xhr-polling, websocket, ...).
+type Transport interface {
+	// Resource returns the name used to identify the transport during the
+	// handshake, e.g. "xhr-polling" or "websocket".
+	Resource() string
+
+	// newSocket creates a new socket that can be used with a connection.
+	newSocket() socket
+}
+
+// defaultWebSocketBufferSize sizes the gorilla upgrader's I/O buffers
+// for the websocket transport DefaultTransports registers. Callers that
+// need origin checking or compression tuned for their deployment should
+// call NewWebSocketTransportGorilla directly instead.
+const defaultWebSocketBufferSize = 4096
+
+// DefaultTransports returns the transports advertised during the
+// handshake out of the box, so clients negotiate xhr-polling,
+// jsonp-polling or websocket automatically. The websocket entry uses
+// gorilla's same-origin default (a nil CheckOrigin) and no compression;
+// callers that need different origin or compression policy should build
+// their own list with NewWebSocketTransportGorilla instead.
+func DefaultTransports(rtimeout, wtimeout time.Duration) []Transport {
+	return []Transport{
+		NewXHRPollingTransport(rtimeout, wtimeout),
+		NewJSONPPollingTransport(rtimeout, wtimeout),
+		NewWebSocketTransportGorilla(defaultWebSocketBufferSize, defaultWebSocketBufferSize, 0, 0, nil),
+	}
+}
@@ -0,0 +1,185 @@
+package socketio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The jsonp-polling transport, used by clients that can't make XHR/CORS
+// requests (strict CSP, old IE, cross-domain iframes without CORS).
+type jsonpPollingTransport struct {
+	rtimeout time.Duration // The period during which the client must send a message.
+	wtimeout time.Duration // The period during which a write must succeed.
+}
+
+// Creates a new jsonp-polling transport with the given read and write timeouts.
+func NewJSONPPollingTransport(rtimeout, wtimeout time.Duration) Transport {
+	return &jsonpPollingTransport{rtimeout, wtimeout}
+}
+
+// Returns the resource name.
+func (t *jsonpPollingTransport) Resource() string {
+	return "jsonp-polling"
+}
+
+// Creates a new socket that can be used with a connection.
+func (t *jsonpPollingTransport) newSocket() socket {
+	return &jsonpPollingSocket{t: t}
+}
+
+// Implements the socket interface for jsonp-polling transports. It
+// behaves like xhr-polling, except the payload is wrapped in a call to
+// the client's JSONP callback and uploads arrive as a urlencoded `d`
+// field rather than a raw body.
+type jsonpPollingSocket struct {
+	t         *jsonpPollingTransport
+	w         http.ResponseWriter
+	flusher   http.Flusher
+	req       *http.Request
+	index     string
+	body      io.Reader
+	connected bool
+}
+
+// String returns the verbose representation of the socket.
+func (s *jsonpPollingSocket) String() string {
+	return s.t.Resource()
+}
+
+// Transport returns the transport the socket is based on.
+func (s *jsonpPollingSocket) Transport() Transport {
+	return s.t
+}
+
+// Context returns the context of the request currently bound to the socket.
+func (s *jsonpPollingSocket) Context() context.Context {
+	if s.req == nil {
+		return context.Background()
+	}
+	return s.req.Context()
+}
+
+// Accepts a http connection & request pair, reading the `i` query
+// parameter that selects which `io.j[N]` callback to wrap responses in.
+func (s *jsonpPollingSocket) accept(w http.ResponseWriter, req *http.Request, proceed func()) error {
+	if s.connected {
+		return ErrConnected
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return ErrUpgradeRequired
+	}
+
+	s.index = req.URL.Query().Get("i")
+	if s.index == "" {
+		s.index = "0"
+	}
+
+	body, err := jsonpRequestBody(req)
+	if err != nil {
+		return err
+	}
+
+	s.req = req
+	s.w = w
+	s.flusher = flusher
+	s.body = body
+	s.connected = true
+
+	proceed()
+	return nil
+}
+
+// jsonpRequestBody returns a reader over the message data for req. GETs
+// (polls) have no body worth reading; POSTs (uploads) carry it
+// urlencoded in the `d` field, per the Socket.IO jsonp-polling spec.
+func jsonpRequestBody(req *http.Request) (io.Reader, error) {
+	if req.Method != http.MethodPost {
+		return req.Body, nil
+	}
+
+	defer req.Body.Close()
+	raw, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := url.ParseQuery(string(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.NewReader(values.Get("d")), nil
+}
+
+func (s *jsonpPollingSocket) Read(p []byte) (int, error) {
+	if !s.connected {
+		return 0, ErrNotConnected
+	}
+
+	return s.body.Read(p)
+}
+
+// Write sends p wrapped as `io.j[N]("...");` with the payload escaped so
+// it's safe to embed inside a double-quoted JavaScript string literal.
+func (s *jsonpPollingSocket) Write(p []byte) (int, error) {
+	if !s.connected {
+		return 0, ErrNotConnected
+	}
+
+	defer func() { s.connected = false }()
+
+	header := s.w.Header()
+	header.Set("Content-Type", "text/javascript; charset=UTF-8")
+	if origin := s.req.Header.Get("Origin"); origin != "" {
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("io.j[")
+	buf.WriteString(s.index)
+	buf.WriteString("](\"")
+	buf.Write(jsonpEscape(p))
+	buf.WriteString("\");")
+
+	s.w.WriteHeader(http.StatusOK)
+	n, err := s.w.Write(buf.Bytes())
+	if err != nil {
+		return n, err
+	}
+
+	s.flusher.Flush()
+	return len(p), nil
+}
+
+var jsonpReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	`'`, `\'`,
+	"\n", `\n`,
+	"\r", `\r`,
+	" ", `\u2028`,
+	" ", `\u2029`,
+)
+
+// jsonpEscape escapes p so it can be embedded inside a double-quoted
+// JavaScript string literal passed to the client's JSONP callback.
+func jsonpEscape(p []byte) []byte {
+	return []byte(jsonpReplacer.Replace(string(p)))
+}
+
+func (s *jsonpPollingSocket) Close() error {
+	if !s.connected {
+		return ErrNotConnected
+	}
+
+	s.connected = false
+	return nil
+}
@@ -0,0 +1,109 @@
+package socketio
+
+import "testing"
+
+func TestJSONPEscapeQuotes(t *testing.T) {
+	// The payload is wrapped in a double-quoted JS string literal, so an
+	// unescaped double quote used to let arbitrary script break out of
+	// it (and out of the enclosing io.j[N](...) call entirely).
+	payload := []byte(`{"hello":"world"}`)
+
+	escaped := jsonpEscape(payload)
+
+	escapedBackslash := false
+	for i, b := range escaped {
+		if b == '"' && !escapedBackslash {
+			t.Fatalf("escaped payload has an unescaped double quote at byte %d: %q", i, escaped)
+		}
+		escapedBackslash = b == '\\' && !escapedBackslash
+	}
+}
+
+func TestJSONPEscapeRoundTripsThroughEval(t *testing.T) {
+	cases := []string{
+		`say "hi"`,
+		`back\slash`,
+		"line\nbreak",
+		"carriage\rreturn",
+		"unicode   separators   too",
+		`it's got a single quote too`,
+	}
+
+	for _, payload := range cases {
+		escaped := string(jsonpEscape([]byte(payload)))
+		js := "\"" + escaped + "\""
+
+		decoded, err := evalJSDoubleQuotedString(js)
+		if err != nil {
+			t.Fatalf("escaping %q produced invalid JS string literal %q: %v", payload, js, err)
+		}
+		if decoded != payload {
+			t.Errorf("round trip of %q produced %q", payload, decoded)
+		}
+	}
+}
+
+// evalJSDoubleQuotedString decodes a double-quoted JavaScript string
+// literal using the same escape sequences jsonpEscape produces, without
+// pulling in a JS engine: it only needs to understand \\, \", \', \n, \r
+// and \uXXXX.
+func evalJSDoubleQuotedString(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", errUnterminatedString
+	}
+	body := s[1 : len(s)-1]
+
+	var out []rune
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '\\' {
+			out = append(out, runes[i])
+			continue
+		}
+		i++
+		if i >= len(runes) {
+			return "", errUnterminatedString
+		}
+		switch runes[i] {
+		case '\\':
+			out = append(out, '\\')
+		case '"':
+			out = append(out, '"')
+		case '\'':
+			out = append(out, '\'')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 'u':
+			if i+4 >= len(runes) {
+				return "", errUnterminatedString
+			}
+			var code int
+			for _, r := range runes[i+1 : i+5] {
+				code *= 16
+				switch {
+				case r >= '0' && r <= '9':
+					code += int(r - '0')
+				case r >= 'a' && r <= 'f':
+					code += int(r-'a') + 10
+				case r >= 'A' && r <= 'F':
+					code += int(r-'A') + 10
+				default:
+					return "", errUnterminatedString
+				}
+			}
+			out = append(out, rune(code))
+			i += 4
+		default:
+			return "", errUnterminatedString
+		}
+	}
+	return string(out), nil
+}
+
+var errUnterminatedString = errUnterminated{}
+
+type errUnterminated struct{}
+
+func (errUnterminated) Error() string { return "unterminated or invalid JS string literal" }
@@ -2,6 +2,8 @@ package socketio
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"net"
@@ -31,10 +33,21 @@ func (t *xhrPollingTransport) newSocket() socket {
 }
 
 // Implements the socket interface for xhr-polling transports.
+//
+// Every poll is served through w/req directly so the connection can be
+// kept alive (HTTP/1.1) or multiplexed (HTTP/2) between polls. Hijacking
+// the TCP connection is only used as a fallback for response writers that
+// can't give us a http.Flusher, since a hijacked connection can't be
+// reused for the client's next poll.
 type xhrPollingSocket struct {
-	t         *xhrPollingTransport
-	rwc       io.ReadWriteCloser
-	req       *http.Request
+	t       *xhrPollingTransport
+	w       http.ResponseWriter
+	flusher http.Flusher
+	req     *http.Request
+
+	// rwc is only set when accept had to fall back to hijacking.
+	rwc io.ReadWriteCloser
+
 	connected bool
 }
 
@@ -48,21 +61,48 @@ func (s *xhrPollingSocket) Transport() Transport {
 	return s.t
 }
 
-// Accepts a http connection & request pair. It hijacks the connection and calls
-// proceed if succesfull.
+// Context returns the context of the request currently bound to the
+// socket. Callers should select on Done() instead of polling a deadline.
+func (s *xhrPollingSocket) Context() context.Context {
+	if s.req == nil {
+		return context.Background()
+	}
+	return s.req.Context()
+}
+
+// Accepts a http connection & request pair. It binds to the
+// http.ResponseWriter for this poll and calls proceed if successful,
+// falling back to hijacking the connection when the writer can't be
+// flushed incrementally (e.g. some HTTP/1.0-only clients).
 func (s *xhrPollingSocket) accept(w http.ResponseWriter, req *http.Request, proceed func()) (err error) {
 	if s.connected {
 		return ErrConnected
 	}
 
 	s.req = req
-	s.rwc, _, err = w.(http.Hijacker).Hijack()
+
+	if flusher, ok := w.(http.Flusher); ok {
+		s.w = w
+		s.flusher = flusher
+		s.connected = true
+		proceed()
+		return nil
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return ErrUpgradeRequired
+	}
+
+	s.rwc, _, err = hijacker.Hijack()
 	if err == nil {
-		if s.t.rtimeout != 0 {
-			s.rwc.(*net.TCPConn).SetReadDeadline(time.Now().Add(s.t.rtimeout))
-		}
-		if s.t.wtimeout != 0 {
-			s.rwc.(*net.TCPConn).SetWriteDeadline(time.Now().Add(s.t.wtimeout))
+		if conn, ok := s.rwc.(*net.TCPConn); ok {
+			if s.t.rtimeout != 0 {
+				conn.SetReadDeadline(time.Now().Add(s.t.rtimeout))
+			}
+			if s.t.wtimeout != 0 {
+				conn.SetWriteDeadline(time.Now().Add(s.t.wtimeout))
+			}
 		}
 		s.connected = true
 		proceed()
@@ -75,34 +115,142 @@ func (s *xhrPollingSocket) Read(p []byte) (int, error) {
 		return 0, ErrNotConnected
 	}
 
-	return s.rwc.Read(p)
+	if s.rwc != nil {
+		return s.rwc.Read(p)
+	}
+	return s.req.Body.Read(p)
 }
 
-// Write sends a single message to the wire and closes the connection.
+// Write sends p as a single text message. It's a thin wrapper around
+// WriteMessages for callers that only deal in plain bytes.
 func (s *xhrPollingSocket) Write(p []byte) (int, error) {
+	_, err := s.WriteMessages([]Message{NewMessage(p)})
+	return len(p), err
+}
+
+// WriteMessages encodes msgs with DefaultCodec and sends them as a
+// single poll response. When serving through http.ResponseWriter the
+// underlying TCP connection is left open for the next poll (HTTP/1.1
+// keep-alive, or multiplexed under HTTP/2); the hijacked fallback path
+// still has to close the connection since nothing else is left to read
+// a new request off of it.
+//
+// Content-Type follows what the client can consume: plain text when
+// nothing is binary, application/octet-stream when something is and the
+// client accepts raw binary responses, or base64-encoded text when
+// something is binary and the request carries a non-empty `b64` query
+// parameter (clients without XHR2 support send `?b64=1`).
+func (s *xhrPollingSocket) WriteMessages(msgs []Message) (int, error) {
 	if !s.connected {
 		return 0, ErrNotConnected
 	}
 
-	defer s.Close()
+	body, contentType, err := s.encode(msgs)
+	if err != nil {
+		return 0, err
+	}
 
-	buf := new(bytes.Buffer)
+	if s.rwc != nil {
+		return s.writeHijacked(body, contentType)
+	}
 
-	buf.WriteString("HTTP/1.0 200 OK\r\n")
-	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
-	fmt.Fprintf(buf, "Content-Length: %d\r\n", len(p))
+	defer func() { s.connected = false }()
 
+	header := s.w.Header()
+	header.Set("Content-Type", contentType)
 	if origin := s.req.Header.Get("Origin"); origin != "" {
-		fmt.Fprintf(buf, "Access-Control-Allow-Origin: %s\r\n", origin)
-		buf.WriteString("Access-Control-Allow-Credentials: true\r\n")
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Set("Access-Control-Allow-Credentials", "true")
 	}
 
-	buf.WriteString("\r\n")
-	buf.Write(p)
+	s.w.WriteHeader(http.StatusOK)
+	n, err := s.w.Write(body)
+	if err != nil {
+		return n, err
+	}
 
-	_, err := buf.WriteTo(s.rwc)
+	s.flusher.Flush()
+	return n, nil
+}
 
-	return len(p), err
+func (s *xhrPollingSocket) encode(msgs []Message) (body []byte, contentType string, err error) {
+	anyBinary := false
+	for _, m := range msgs {
+		if m.Binary() {
+			anyBinary = true
+			break
+		}
+	}
+
+	// A lone text message needs no framing at all: send it exactly as
+	// every xhr-polling client has always expected. DefaultCodec's
+	// length-prefixed framing only earns its keep once there's more than
+	// one packet to tell apart, or a binary one to mark.
+	if !anyBinary && len(msgs) <= 1 {
+		if len(msgs) == 0 {
+			return nil, "text/plain; charset=UTF-8", nil
+		}
+		return msgs[0].Data(), "text/plain; charset=UTF-8", nil
+	}
+
+	if anyBinary && s.req.URL.Query().Get("b64") != "" {
+		body, err = encodeBase64Payload(msgs)
+		return body, "text/plain; charset=UTF-8", err
+	}
+
+	contentType = "text/plain; charset=UTF-8"
+	if anyBinary {
+		contentType = "application/octet-stream"
+	}
+
+	buf := new(bytes.Buffer)
+	if err = DefaultCodec.Encode(buf, msgs); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+// encodeBase64Payload frames each of msgs as "<len>:<payload>" for text
+// packets and "b<len>:<base64>" for binary ones, with <len> counting the
+// bytes that follow the colon, so the whole payload stays valid text for
+// clients that can't accept a binary response body.
+func encodeBase64Payload(msgs []Message) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for _, m := range msgs {
+		data := m.Data()
+		prefix := ""
+		if m.Binary() {
+			prefix = "b"
+			data = []byte(base64.StdEncoding.EncodeToString(data))
+		}
+		fmt.Fprintf(buf, "%s%d:", prefix, len(data))
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeHijacked is the legacy write path used when the response writer
+// couldn't be flushed incrementally. It has to speak raw HTTP/1.0 and
+// close the connection, since the hijacked net.Conn gives up any chance
+// of the server reusing it for a later request.
+func (s *xhrPollingSocket) writeHijacked(body []byte, contentType string) (int, error) {
+	defer s.Close()
+
+	buf := make([]byte, 0, len(body)+128)
+	buf = append(buf, "HTTP/1.0 200 OK\r\n"...)
+	buf = append(buf, fmt.Sprintf("Content-Type: %s\r\n", contentType)...)
+	buf = append(buf, fmt.Sprintf("Content-Length: %d\r\n", len(body))...)
+
+	if origin := s.req.Header.Get("Origin"); origin != "" {
+		buf = append(buf, fmt.Sprintf("Access-Control-Allow-Origin: %s\r\n", origin)...)
+		buf = append(buf, "Access-Control-Allow-Credentials: true\r\n"...)
+	}
+
+	buf = append(buf, "\r\n"...)
+	buf = append(buf, body...)
+
+	_, err := s.rwc.Write(buf)
+	return len(body), err
 }
 
 func (s *xhrPollingSocket) Close() error {
@@ -111,5 +259,8 @@ func (s *xhrPollingSocket) Close() error {
 	}
 
 	s.connected = false
-	return s.rwc.Close()
+	if s.rwc != nil {
+		return s.rwc.Close()
+	}
+	return nil
 }